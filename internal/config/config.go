@@ -5,26 +5,62 @@ import (
 	"errors"
 	"os"
 	"sync"
+	"sync/atomic"
 )
 
+// ProxyConfig 描述一跳代理；Chain 中的每一跳按顺序叠加在上一跳之上，
+// 最终请求先经过 ProxyConfig 自身，再依次经过 Chain 里的各跳。
 type ProxyConfig struct {
-	Enabled bool   `json:"enabled"`
-	Type    string `json:"type"` // socks5
-	Host    string `json:"host"`
-	Port    string `json:"port"`
-	User    string `json:"user"`
-	Pass    string `json:"pass"`
+	Enabled bool          `json:"enabled"`
+	Type    string        `json:"type"` // socks5 / socks4 / http / https
+	Host    string        `json:"host"`
+	Port    string        `json:"port"`
+	User    string        `json:"user"`
+	Pass    string        `json:"pass"`
+	Chain   []ProxyConfig `json:"chain,omitempty"`
+}
+
+// Database 描述连接信息的持久化后端配置，driver 为空时默认使用 SQLite。
+type Database struct {
+	Driver  string `json:"driver"`   // sqlite / mysql / postgres
+	DSN     string `json:"dsn"`      // 连接串，sqlite 下留空则使用默认文件路径
+	MaxOpen int    `json:"max_open"` // 最大打开连接数，0 表示使用驱动默认值
+	MaxIdle int    `json:"max_idle"` // 最大空闲连接数，0 表示使用驱动默认值
+}
+
+// Runner 控制批量连接执行器的并发度、限速与重试策略。
+type Runner struct {
+	MaxConcurrent               int `json:"max_concurrent"`                   // 工作池容量，0 表示使用默认值
+	MaxAttemptsPerHostPerMinute int `json:"max_attempts_per_host_per_minute"` // 单个 ip:port 每分钟最多尝试次数
+	DialTimeoutSeconds          int `json:"dial_timeout_seconds"`             // 单次拨号超时
+	MaxRetries                  int `json:"max_retries"`                      // 失败后的最大重试次数
+	BackoffBaseMillis           int `json:"backoff_base_millis"`              // 指数退避的基础间隔
+	BackoffMaxMillis            int `json:"backoff_max_millis"`               // 退避间隔上限
+}
+
+// Security 控制凭据静态加密。主密钥优先从环境变量 BATCH_CONNECTOR_KEY 读取，
+// 未设置时回退到 KeyFile 指向的文件；两者都缺失则不启用加密。
+type Security struct {
+	KeyFile     string `json:"key_file"`
+	EncryptUser bool   `json:"encrypt_user"` // 是否同时加密 user 字段，默认只加密 pass
 }
 
 type Config struct {
 	Password string      `json:"password"`
 	Port     string      `json:"port"`
 	Proxy    ProxyConfig `json:"proxy"`
+	// ProxyPerType 按连接类型（如 "ssh"、"rdp"）覆盖默认代理，未命中的类型回退到 Proxy。
+	ProxyPerType map[string]ProxyConfig `json:"proxy_per_type,omitempty"`
+	Database     Database               `json:"database"`
+	Runner       Runner                 `json:"runner"`
+	Security     Security               `json:"security"`
 }
 
 var (
-	instance *Config
-	lock     sync.RWMutex
+	// instance 是无锁读取的配置单例。loadLock 只串行化 LoadConfig 的首次加载，避免并发加载时
+	// 重复解析文件；SaveConfig 与热重载直接原子替换 instance，不获取 loadLock。
+	instance atomic.Pointer[Config]
+	loadLock sync.Mutex
 )
 
 func defaultConfig() *Config {
@@ -47,14 +83,55 @@ func normalizeConfig(cfg *Config) {
 	if cfg.Password == "" {
 		cfg.Password = "admin123"
 	}
-	if cfg.Proxy.Type == "" {
-		cfg.Proxy.Type = "socks5"
+	normalizeProxy(&cfg.Proxy)
+	for connType, proxyCfg := range cfg.ProxyPerType {
+		normalizeProxy(&proxyCfg)
+		cfg.ProxyPerType[connType] = proxyCfg
+	}
+	if cfg.Database.Driver == "" {
+		cfg.Database.Driver = "sqlite"
+	}
+	normalizeRunner(&cfg.Runner)
+}
+
+// normalizeRunner 为执行器配置补全默认值。
+func normalizeRunner(r *Runner) {
+	if r.MaxConcurrent <= 0 {
+		r.MaxConcurrent = 10
+	}
+	if r.MaxAttemptsPerHostPerMinute <= 0 {
+		r.MaxAttemptsPerHostPerMinute = 30
+	}
+	if r.DialTimeoutSeconds <= 0 {
+		r.DialTimeoutSeconds = 5
+	}
+	if r.MaxRetries <= 0 {
+		r.MaxRetries = 3
+	}
+	if r.BackoffBaseMillis <= 0 {
+		r.BackoffBaseMillis = 500
+	}
+	if r.BackoffMaxMillis <= 0 {
+		r.BackoffMaxMillis = 10000
 	}
 }
 
+// normalizeProxy 为单跳代理配置补全默认类型，Chain 中的每一跳同样需要归一化。
+func normalizeProxy(p *ProxyConfig) {
+	if p.Type == "" {
+		p.Type = "socks5"
+	}
+	for i := range p.Chain {
+		normalizeProxy(&p.Chain[i])
+	}
+}
+
+// configFileName 是配置文件相对当前工作目录的路径，LoadConfig/SaveConfig/Watcher 共用。
+const configFileName = "config.json"
+
 func loadFromFile() (*Config, error) {
 	cfg := defaultConfig()
-	data, err := os.ReadFile("config.json")
+	data, err := os.ReadFile(configFileName)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			normalizeConfig(cfg)
@@ -70,13 +147,17 @@ func loadFromFile() (*Config, error) {
 	return cfg, nil
 }
 
-// LoadConfig 加载配置文件
+// LoadConfig 加载配置文件，首次调用后的结果被缓存在 instance 中供 GetConfig 无锁读取。
 func LoadConfig() (*Config, error) {
-	lock.Lock()
-	defer lock.Unlock()
+	if cfg := instance.Load(); cfg != nil {
+		return cfg, nil
+	}
+
+	loadLock.Lock()
+	defer loadLock.Unlock()
 
-	if instance != nil {
-		return instance, nil
+	if cfg := instance.Load(); cfg != nil {
+		return cfg, nil
 	}
 
 	cfg, err := loadFromFile()
@@ -84,24 +165,20 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
-	instance = cfg
+	instance.Store(cfg)
 	return cfg, nil
 }
 
-// GetConfig 获取配置实例
+// GetConfig 获取配置实例；配置加载完成后读路径无锁，尚未加载时经 LoadConfig 加载（会获取 loadLock）
 func GetConfig() *Config {
-	lock.RLock()
-	if instance != nil {
-		cfg := instance
-		lock.RUnlock()
+	if cfg := instance.Load(); cfg != nil {
 		return cfg
 	}
-	lock.RUnlock()
 	cfg, _ := LoadConfig()
 	return cfg
 }
 
-// SaveConfig 保存配置并更新内存实例
+// SaveConfig 保存配置并原子替换内存实例
 func SaveConfig(cfg *Config) error {
 	if cfg == nil {
 		return errors.New("config is nil")
@@ -111,11 +188,9 @@ func SaveConfig(cfg *Config) error {
 	if err != nil {
 		return err
 	}
-	if err := os.WriteFile("config.json", data, 0644); err != nil {
+	if err := os.WriteFile(configFileName, data, 0644); err != nil {
 		return err
 	}
-	lock.Lock()
-	instance = cfg
-	lock.Unlock()
+	instance.Store(cfg)
 	return nil
 }
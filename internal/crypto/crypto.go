@@ -0,0 +1,115 @@
+// Package crypto 提供凭据静态加密所需的 AES-256-GCM 原语。
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// MasterKeyEnv 是主密钥的环境变量名，优先级高于 config.Security.KeyFile。
+const MasterKeyEnv = "BATCH_CONNECTOR_KEY"
+
+const keySize = 32 // AES-256
+
+// Cryptor 使用主密钥对字符串做 AES-256-GCM 加解密。
+type Cryptor struct {
+	gcm cipher.AEAD
+}
+
+// NewCryptor 用给定的 32 字节主密钥构造 Cryptor。
+func NewCryptor(key []byte) (*Cryptor, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("主密钥长度必须为 %d 字节，当前为 %d", keySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 AES 失败: %v", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 GCM 失败: %v", err)
+	}
+
+	return &Cryptor{gcm: gcm}, nil
+}
+
+// Encrypt 加密明文，返回 base64(nonce || ciphertext)。
+func (c *Cryptor) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("生成 nonce 失败: %v", err)
+	}
+
+	sealed := c.gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 产出的 base64(nonce || ciphertext)。
+func (c *Cryptor) Decrypt(encoded string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("base64 解码失败: %v", err)
+	}
+
+	nonceSize := c.gcm.NonceSize()
+	if len(data) < nonceSize {
+		return "", errors.New("密文长度不足")
+	}
+
+	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
+	plaintext, err := c.gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("解密失败: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// LooksEncrypted 判断字符串是否符合 Encrypt 的输出格式（base64 且长度足以容纳 nonce）。
+// 用于识别迁移前遗留的明文行。
+func (c *Cryptor) LooksEncrypted(s string) bool {
+	if s == "" {
+		return false
+	}
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return false
+	}
+	return len(data) > c.gcm.NonceSize()
+}
+
+// LoadMasterKey 按优先级加载主密钥：环境变量 BATCH_CONNECTOR_KEY，其次是 keyFile 指向的文件。
+// 密钥内容可以是 base64 编码，也可以是原始 32 字节。
+func LoadMasterKey(keyFile string) ([]byte, error) {
+	if v := os.Getenv(MasterKeyEnv); v != "" {
+		return decodeKeyMaterial(v)
+	}
+
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取密钥文件失败: %v", err)
+		}
+		return decodeKeyMaterial(strings.TrimSpace(string(data)))
+	}
+
+	return nil, nil
+}
+
+func decodeKeyMaterial(material string) ([]byte, error) {
+	if decoded, err := base64.StdEncoding.DecodeString(material); err == nil && len(decoded) == keySize {
+		return decoded, nil
+	}
+	if len(material) == keySize {
+		return []byte(material), nil
+	}
+	return nil, fmt.Errorf("主密钥必须是 %d 字节原始内容或其 base64 编码", keySize)
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"batch-connector/internal/config"
+	"batch-connector/internal/models"
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RunnerStats 是 BatchRunner 在某一时刻的运行快照。
+type RunnerStats struct {
+	InFlight      int64
+	Succeeded     int64
+	Failed        int64
+	RatePerMinute float64
+}
+
+// BatchRunner 从存储中拉取 status='pending' 的连接，按并发度与限速策略逐个拨测。
+type BatchRunner struct {
+	connector *ConnectorService
+	cfg       config.Runner
+	limiter   *hostRateLimiter
+
+	mu        sync.Mutex
+	cancel    context.CancelFunc
+	startedAt time.Time
+	paused    int32
+
+	wg sync.WaitGroup
+
+	inFlight  int64
+	succeeded int64
+	failed    int64
+}
+
+// NewBatchRunner 创建一个批处理执行器，cfg 控制并发度、限速与重试策略。
+func NewBatchRunner(connector *ConnectorService, cfg config.Runner) *BatchRunner {
+	return &BatchRunner{
+		connector: connector,
+		cfg:       cfg,
+		limiter:   newHostRateLimiter(cfg.MaxAttemptsPerHostPerMinute),
+	}
+}
+
+// Start 启动轮询与调度循环，重复调用在已运行时返回错误。
+func (r *BatchRunner) Start(ctx context.Context) error {
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.mu.Unlock()
+		return fmt.Errorf("批处理执行器已在运行")
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+	r.startedAt = time.Now()
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go r.loop(runCtx)
+	return nil
+}
+
+// Stop 停止调度循环并等待所有在途任务结束。
+func (r *BatchRunner) Stop() {
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+}
+
+// Pause 暂停从存储中拉取新的待处理连接，已分发的任务不受影响。
+func (r *BatchRunner) Pause() {
+	atomic.StoreInt32(&r.paused, 1)
+}
+
+// Resume 恢复调度循环。
+func (r *BatchRunner) Resume() {
+	atomic.StoreInt32(&r.paused, 0)
+}
+
+// Stats 返回当前的执行统计快照。
+func (r *BatchRunner) Stats() RunnerStats {
+	return RunnerStats{
+		InFlight:      atomic.LoadInt64(&r.inFlight),
+		Succeeded:     atomic.LoadInt64(&r.succeeded),
+		Failed:        atomic.LoadInt64(&r.failed),
+		RatePerMinute: r.currentRate(),
+	}
+}
+
+func (r *BatchRunner) currentRate() float64 {
+	r.mu.Lock()
+	started := r.startedAt
+	r.mu.Unlock()
+
+	if started.IsZero() {
+		return 0
+	}
+	elapsedMinutes := time.Since(started).Minutes()
+	if elapsedMinutes <= 0 {
+		return 0
+	}
+	total := float64(atomic.LoadInt64(&r.succeeded) + atomic.LoadInt64(&r.failed))
+	return total / elapsedMinutes
+}
+
+// loop 周期性地拉取待处理连接，并在工作池容量允许时分发拨测任务。
+func (r *BatchRunner) loop(ctx context.Context) {
+	defer r.wg.Done()
+
+	sem := make(chan struct{}, r.cfg.MaxConcurrent)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if atomic.LoadInt32(&r.paused) == 1 {
+				continue
+			}
+
+			for _, conn := range r.connector.GetPendingConnections() {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+
+				r.wg.Add(1)
+				go func(c *models.Connection) {
+					defer r.wg.Done()
+					defer func() { <-sem }()
+					r.attempt(ctx, c)
+				}(conn)
+			}
+		}
+	}
+}
+
+// attempt 对单个连接执行拨测，失败时按退避策略重试，最终标记 status='success'/'failed'。
+func (r *BatchRunner) attempt(ctx context.Context, conn *models.Connection) {
+	atomic.AddInt64(&r.inFlight, 1)
+	defer atomic.AddInt64(&r.inFlight, -1)
+
+	conn.Status = "running"
+	_ = r.connector.UpdateConnection(conn)
+
+	address := net.JoinHostPort(conn.IP, conn.Port)
+
+	var lastErr error
+	for attemptNum := 1; attemptNum <= r.cfg.MaxRetries+1; attemptNum++ {
+		if attemptNum > 1 {
+			select {
+			case <-time.After(backoffDuration(r.cfg, attemptNum-1)):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		for !r.limiter.Allow(address) {
+			select {
+			case <-time.After(200 * time.Millisecond):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		dialCtx, cancel := context.WithTimeout(ctx, time.Duration(r.cfg.DialTimeoutSeconds)*time.Second)
+		netConn, err := r.connector.dialContextWithProxy(dialCtx, conn.Type, "tcp", address)
+		cancel()
+
+		if err == nil {
+			netConn.Close()
+			conn.Status = "success"
+			conn.ConnectedAt = time.Now()
+			conn.Logs = append(conn.Logs, fmt.Sprintf("[%s] 第 %d 次尝试连接成功", time.Now().Format(time.RFC3339), attemptNum))
+			_ = r.connector.UpdateConnection(conn)
+			atomic.AddInt64(&r.succeeded, 1)
+			return
+		}
+
+		lastErr = err
+		conn.Logs = append(conn.Logs, fmt.Sprintf("[%s] 第 %d 次尝试失败: %v", time.Now().Format(time.RFC3339), attemptNum, err))
+		_ = r.connector.UpdateConnection(conn)
+	}
+
+	conn.Status = "failed"
+	conn.Message = fmt.Sprintf("重试 %d 次后仍失败: %v", r.cfg.MaxRetries, lastErr)
+	_ = r.connector.UpdateConnection(conn)
+	atomic.AddInt64(&r.failed, 1)
+}
+
+// backoffDuration 按指数退避加随机抖动计算第 attemptNum 次重试前的等待时间。
+func backoffDuration(cfg config.Runner, attemptNum int) time.Duration {
+	base := time.Duration(cfg.BackoffBaseMillis) * time.Millisecond
+	maxDelay := time.Duration(cfg.BackoffMaxMillis) * time.Millisecond
+
+	delay := base << uint(attemptNum-1)
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	return delay/2 + jitter
+}
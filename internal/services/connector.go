@@ -2,295 +2,159 @@ package services
 
 import (
 	"batch-connector/internal/config"
+	"batch-connector/internal/crypto"
 	"batch-connector/internal/models"
+	"batch-connector/internal/storage"
 	"context"
-	"database/sql"
-	"encoding/json"
 	"fmt"
-	"net"
-	"strings"
+	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/net/proxy"
 )
 
 type ConnectorService struct {
-	db     *sql.DB
-	config *config.Config
+	store         storage.Storage
+	config        atomic.Pointer[config.Config]
+	configWatcher *config.Watcher
 }
 
 func NewConnectorService() (*ConnectorService, error) {
-	db, err := initDatabase()
-	if err != nil {
-		return nil, fmt.Errorf("初始化数据库失败: %v", err)
-	}
-
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return nil, fmt.Errorf("加载配置失败: %v", err)
 	}
 
-	return &ConnectorService{
-		db:     db,
-		config: cfg,
-	}, nil
-}
-
-// UpdateConfig 更新运行时配置
-func (s *ConnectorService) UpdateConfig(cfg *config.Config) {
-	if cfg == nil {
-		return
-	}
-	s.config = cfg
-}
-
-// getProxyDialer 获取代理 Dialer，如果代理未启用则返回 nil
-func (s *ConnectorService) getProxyDialer() (proxy.Dialer, error) {
-	if !s.config.Proxy.Enabled {
-		return nil, nil
+	codec, err := newCredentialCodec(&cfg.Security)
+	if err != nil {
+		return nil, fmt.Errorf("初始化凭据加密失败: %v", err)
 	}
 
-	if s.config.Proxy.Type != "socks5" {
-		return nil, fmt.Errorf("不支持的代理类型: %s", s.config.Proxy.Type)
+	store, err := storage.New(&cfg.Database, codec)
+	if err != nil {
+		return nil, fmt.Errorf("初始化数据库失败: %v", err)
 	}
 
-	proxyAddr := net.JoinHostPort(s.config.Proxy.Host, s.config.Proxy.Port)
+	s := &ConnectorService{store: store}
+	s.config.Store(cfg)
+	s.watchConfig()
 
-	var auth *proxy.Auth
-	if s.config.Proxy.User != "" {
-		auth = &proxy.Auth{
-			User:     s.config.Proxy.User,
-			Password: s.config.Proxy.Pass,
-		}
-	}
+	return s, nil
+}
 
-	dialer, err := proxy.SOCKS5("tcp", proxyAddr, auth, proxy.Direct)
+// watchConfig 启动配置文件监听，并在收到热重载后原子更新 s.config。
+// 监听器创建失败不影响服务启动，仅记录日志——该环境下热重载视为不可用。
+func (s *ConnectorService) watchConfig() {
+	watcher, err := config.NewWatcher()
 	if err != nil {
-		return nil, fmt.Errorf("创建 SOCKS5 代理 Dialer 失败: %v", err)
+		log.Printf("启动配置热重载监听失败，将继续使用静态配置: %v", err)
+		return
 	}
 
-	return dialer, nil
+	s.configWatcher = watcher
+	watcher.Start(context.Background())
+
+	go func() {
+		for newCfg := range watcher.Updates() {
+			s.UpdateConfig(newCfg)
+		}
+	}()
 }
 
-// dialWithProxy 通过代理或直接连接目标地址
-func (s *ConnectorService) dialWithProxy(network, address string) (net.Conn, error) {
-	proxyDialer, err := s.getProxyDialer()
+// newCredentialCodec 按配置加载主密钥并构造凭据加解密器；未配置密钥时返回的 codec 按明文直通。
+func newCredentialCodec(sec *config.Security) (*storage.CredentialCipher, error) {
+	key, err := crypto.LoadMasterKey(sec.KeyFile)
 	if err != nil {
 		return nil, err
 	}
-
-	if proxyDialer != nil {
-		return proxyDialer.Dial(network, address)
-	}
-
-	// 没有代理，直接连接
-	dialer := &net.Dialer{
-		Timeout: 5 * time.Second,
+	if key == nil {
+		log.Printf("未配置主密钥(%s)，凭据将以明文存储", crypto.MasterKeyEnv)
+		return storage.NewCredentialCipher(nil, false), nil
 	}
-	return dialer.Dial(network, address)
-}
 
-// dialContextWithProxy 通过代理或直接连接目标地址（带 Context）
-func (s *ConnectorService) dialContextWithProxy(ctx context.Context, network, address string) (net.Conn, error) {
-	proxyDialer, err := s.getProxyDialer()
+	cryptor, err := crypto.NewCryptor(key)
 	if err != nil {
 		return nil, err
 	}
+	return storage.NewCredentialCipher(cryptor, sec.EncryptUser), nil
+}
 
-	if proxyDialer != nil {
-		if contextDialer, ok := proxyDialer.(proxy.ContextDialer); ok {
-			return contextDialer.DialContext(ctx, network, address)
-		}
-		// 如果不支持 Context，使用普通 Dial
-		return proxyDialer.Dial(network, address)
+// UpdateConfig 原子更新运行时配置，可在任意 goroutine 中安全调用
+func (s *ConnectorService) UpdateConfig(cfg *config.Config) {
+	if cfg == nil {
+		return
 	}
+	s.config.Store(cfg)
+}
 
-	// 没有代理，直接连接
-	dialer := &net.Dialer{
-		Timeout: 5 * time.Second,
-	}
-	return dialer.DialContext(ctx, network, address)
+// cfg 返回当前生效的配置快照
+func (s *ConnectorService) cfg() *config.Config {
+	return s.config.Load()
 }
 
 // AddConnection 添加连接信息
 func (s *ConnectorService) AddConnection(conn *models.Connection) error {
-	values, err := connectionToValues(conn)
-	if err != nil {
-		return fmt.Errorf("序列化连接数据失败: %v", err)
-	}
-
-	insertSQL := `INSERT INTO connections 
-		(id, type, ip, port, user, pass, status, message, result, logs, created_at, connected_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-
-	_, err = s.db.Exec(insertSQL, values...)
-	if err != nil {
-		return fmt.Errorf("插入连接失败: %v", err)
-	}
-
-	return nil
+	return s.store.Add(conn)
 }
 
-// GetConnection 获取连接信息
-func (s *ConnectorService) GetConnection(id string) (*models.Connection, bool) {
-	querySQL := `SELECT id, type, ip, port, user, pass, status, message, result, logs, created_at, connected_at
-		FROM connections WHERE id = ?`
-
-	row := s.db.QueryRow(querySQL, id)
-	conn, err := connectionFromRow(row)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, false
-		}
-		return nil, false
-	}
-
-	return conn, true
+// GetConnection 获取连接信息；reveal 为 false 时密码以 **** 掩码返回，
+// 只有显式传入 true 才会触发解密，避免调用方意外拿到明文。
+func (s *ConnectorService) GetConnection(id string, reveal bool) (*models.Connection, bool) {
+	return s.store.Get(id, reveal)
 }
 
-// GetAllConnections 获取所有连接信息
-func (s *ConnectorService) GetAllConnections() []*models.Connection {
-	querySQL := `SELECT id, type, ip, port, user, pass, status, message, result, logs, created_at, connected_at
-		FROM connections ORDER BY created_at DESC`
-
-	rows, err := s.db.Query(querySQL)
-	if err != nil {
-		return []*models.Connection{}
-	}
-	defer rows.Close()
-
-	connections := []*models.Connection{}
-	for rows.Next() {
-		conn, err := connectionFromRows(rows)
-		if err != nil {
-			continue
-		}
-		connections = append(connections, conn)
-	}
+// GetConnectionMasked 获取连接信息但始终掩码密码，供列表类只读场景使用。
+func (s *ConnectorService) GetConnectionMasked(id string) (*models.Connection, bool) {
+	return s.store.Get(id, false)
+}
 
-	return connections
+// GetAllConnections 获取所有连接信息；reveal 控制是否解密密码
+func (s *ConnectorService) GetAllConnections(reveal bool) []*models.Connection {
+	return s.store.List(reveal)
 }
 
-// GetConnectionsByType 按类型获取连接
-func (s *ConnectorService) GetConnectionsByType(connType string) []*models.Connection {
-	querySQL := `SELECT id, type, ip, port, user, pass, status, message, result, logs, created_at, connected_at
-		FROM connections WHERE type = ? ORDER BY created_at DESC`
+// GetAllConnectionsMasked 获取所有连接信息，密码始终掩码，供批量列表接口使用
+func (s *ConnectorService) GetAllConnectionsMasked() []*models.Connection {
+	return s.store.List(false)
+}
 
-	rows, err := s.db.Query(querySQL, connType)
-	if err != nil {
-		return []*models.Connection{}
-	}
-	defer rows.Close()
+// GetConnectionsByType 按类型获取连接；reveal 控制是否解密密码
+func (s *ConnectorService) GetConnectionsByType(connType string, reveal bool) []*models.Connection {
+	return s.store.ListByType(connType, reveal)
+}
 
-	connections := []*models.Connection{}
-	for rows.Next() {
-		conn, err := connectionFromRows(rows)
-		if err != nil {
-			continue
+// GetPendingConnections 获取所有待处理（status='pending'）的连接，供 BatchRunner 调度，
+// 始终按 reveal=true 读取，返回的 user/pass 为解密后的明文。
+func (s *ConnectorService) GetPendingConnections() []*models.Connection {
+	all := s.store.List(true)
+	pending := make([]*models.Connection, 0, len(all))
+	for _, conn := range all {
+		if conn.Status == "pending" {
+			pending = append(pending, conn)
 		}
-		connections = append(connections, conn)
 	}
-
-	return connections
+	return pending
 }
 
 // DeleteConnection 删除连接
 func (s *ConnectorService) DeleteConnection(id string) bool {
-	deleteSQL := `DELETE FROM connections WHERE id = ?`
-	result, err := s.db.Exec(deleteSQL, id)
-	if err != nil {
-		return false
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return false
-	}
-
-	return rowsAffected > 0
+	return s.store.Delete(id)
 }
 
 // UpdateConnection 更新连接信息（用于更新状态、日志等）
 func (s *ConnectorService) UpdateConnection(conn *models.Connection) error {
-	// 序列化日志
-	logsJSON := "[]"
-	if conn.Logs != nil && len(conn.Logs) > 0 {
-		jsonData, err := json.Marshal(conn.Logs)
-		if err != nil {
-			return fmt.Errorf("序列化日志失败: %v", err)
-		}
-		logsJSON = string(jsonData)
-	}
-
-	// 格式化时间
-	connectedAtStr := ""
-	if !conn.ConnectedAt.IsZero() {
-		connectedAtStr = conn.ConnectedAt.Format(time.RFC3339)
-	}
-
-	updateSQL := `UPDATE connections SET 
-		status = ?, message = ?, result = ?, logs = ?, connected_at = ?
-		WHERE id = ?`
-
-	_, err := s.db.Exec(updateSQL,
-		conn.Status,
-		conn.Message,
-		conn.Result,
-		logsJSON,
-		connectedAtStr,
-		conn.ID,
-	)
-	if err != nil {
-		return fmt.Errorf("更新连接失败: %v", err)
-	}
-
-	return nil
+	return s.store.Update(conn)
 }
 
 // UpdateConnectionInfo 更新连接基本信息（type, ip, port, user, pass）
 func (s *ConnectorService) UpdateConnectionInfo(id, connType, ip, port, user, pass string) error {
-	updateSQL := `UPDATE connections SET 
-		type = ?, ip = ?, port = ?, user = ?, pass = ?
-		WHERE id = ?`
-
-	_, err := s.db.Exec(updateSQL, connType, ip, port, user, pass, id)
-	if err != nil {
-		return fmt.Errorf("更新连接信息失败: %v", err)
-	}
-
-	return nil
+	return s.store.UpdateInfo(id, connType, ip, port, user, pass)
 }
 
 // DeleteBatchConnections 批量删除连接
 func (s *ConnectorService) DeleteBatchConnections(ids []string) (int, error) {
-	if len(ids) == 0 {
-		return 0, nil
-	}
-
-	// 构建占位符
-	placeholders := strings.Repeat("?,", len(ids))
-	placeholders = placeholders[:len(placeholders)-1] // 移除最后一个逗号
-
-	deleteSQL := fmt.Sprintf("DELETE FROM connections WHERE id IN (%s)", placeholders)
-
-	// 将 []string 转换为 []interface{}
-	args := make([]interface{}, len(ids))
-	for i, id := range ids {
-		args[i] = id
-	}
-
-	result, err := s.db.Exec(deleteSQL, args...)
-	if err != nil {
-		return 0, fmt.Errorf("批量删除连接失败: %v", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return 0, fmt.Errorf("获取删除行数失败: %v", err)
-	}
-
-	return int(rowsAffected), nil
+	return s.store.DeleteBatch(ids)
 }
 
 // CreateConnectionFromCSV 从 CSV 数据创建连接
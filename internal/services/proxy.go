@@ -0,0 +1,308 @@
+package services
+
+import (
+	"batch-connector/internal/config"
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+func init() {
+	// 允许通过 proxy.FromURL("socks4://...") 构造 SOCKS4 拨号器，与 golang.org/x/net/proxy
+	// 内置的 socks5 方案使用同一套 URL 注册机制。
+	proxy.RegisterDialerType("socks4", func(u *url.URL, forward proxy.Dialer) (proxy.Dialer, error) {
+		var auth *proxy.Auth
+		if u.User != nil {
+			pass, _ := u.User.Password()
+			auth = &proxy.Auth{User: u.User.Username(), Password: pass}
+		}
+		return newSOCKS4Dialer(u.Host, auth, forward), nil
+	})
+}
+
+// resolveProxyChain 根据连接类型选出生效的代理配置：
+// ProxyPerType 中存在且启用的条目优先，否则回退到默认 Proxy。
+func (s *ConnectorService) resolveProxyChain(connType string) []config.ProxyConfig {
+	cfg := s.cfg()
+	base := cfg.Proxy
+	if cfg.ProxyPerType != nil {
+		if override, ok := cfg.ProxyPerType[connType]; ok && override.Enabled {
+			base = override
+		}
+	}
+
+	if !base.Enabled {
+		return nil
+	}
+
+	hops := make([]config.ProxyConfig, 0, 1+len(base.Chain))
+	hops = append(hops, base)
+	hops = append(hops, base.Chain...)
+	return hops
+}
+
+// getProxyDialer 按连接类型选取代理链并构建出 Dialer，如果未启用代理则返回 nil。
+func (s *ConnectorService) getProxyDialer(connType string) (proxy.Dialer, error) {
+	hops := s.resolveProxyChain(connType)
+	if len(hops) == 0 {
+		return nil, nil
+	}
+
+	var dialer proxy.Dialer = proxy.Direct
+	for _, hop := range hops {
+		var err error
+		dialer, err = buildHopDialer(hop, dialer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dialer, nil
+}
+
+// buildHopDialer 在 forward 之上叠加一跳代理，forward 为 nil 时等价于直连。
+func buildHopDialer(hop config.ProxyConfig, forward proxy.Dialer) (proxy.Dialer, error) {
+	addr := net.JoinHostPort(hop.Host, hop.Port)
+
+	var auth *proxy.Auth
+	if hop.User != "" {
+		auth = &proxy.Auth{User: hop.User, Password: hop.Pass}
+	}
+
+	switch hop.Type {
+	case "", "socks5":
+		dialer, err := proxy.SOCKS5("tcp", addr, auth, forward)
+		if err != nil {
+			return nil, fmt.Errorf("创建 SOCKS5 代理 Dialer 失败: %v", err)
+		}
+		return dialer, nil
+	case "socks4":
+		return newSOCKS4Dialer(addr, auth, forward), nil
+	case "http":
+		return newHTTPConnectDialer(addr, auth, forward, false), nil
+	case "https":
+		return newHTTPConnectDialer(addr, auth, forward, true), nil
+	default:
+		return nil, fmt.Errorf("不支持的代理类型: %s", hop.Type)
+	}
+}
+
+// dialWithProxy 通过连接类型对应的代理链或直接连接目标地址
+func (s *ConnectorService) dialWithProxy(connType, network, address string) (net.Conn, error) {
+	proxyDialer, err := s.getProxyDialer(connType)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyDialer != nil {
+		return proxyDialer.Dial(network, address)
+	}
+
+	// 没有代理，直接连接
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+	}
+	return dialer.Dial(network, address)
+}
+
+// dialContextWithProxy 通过连接类型对应的代理链或直接连接目标地址（带 Context）
+func (s *ConnectorService) dialContextWithProxy(ctx context.Context, connType, network, address string) (net.Conn, error) {
+	proxyDialer, err := s.getProxyDialer(connType)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyDialer != nil {
+		if contextDialer, ok := proxyDialer.(proxy.ContextDialer); ok {
+			return contextDialer.DialContext(ctx, network, address)
+		}
+		// 如果不支持 Context，使用普通 Dial
+		return proxyDialer.Dial(network, address)
+	}
+
+	// 没有代理，直接连接
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// dialForward 通过上游 Dialer 拨号，优先使用支持 Context 的实现。
+func dialForward(ctx context.Context, forward proxy.Dialer, network, addr string) (net.Conn, error) {
+	if contextDialer, ok := forward.(proxy.ContextDialer); ok {
+		return contextDialer.DialContext(ctx, network, addr)
+	}
+	return forward.Dial(network, addr)
+}
+
+// withHandshakeDeadline 将 ctx 的截止时间应用到 conn 上，覆盖代理握手阶段的读写，
+// 避免一个只完成 TCP 连接、随后不再应答的代理让调用方无限阻塞。返回的 done 用于在
+// 握手结束后清除该 deadline，不影响后续业务数据的读写超时策略。
+func withHandshakeDeadline(ctx context.Context, conn net.Conn) (done func()) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return func() {}
+	}
+	conn.SetDeadline(deadline)
+	return func() { conn.SetDeadline(time.Time{}) }
+}
+
+// --- SOCKS4 ---
+
+// socks4Dialer 是一个最小化的 SOCKS4/SOCKS4A 客户端实现，forward 为上一跳代理（或直连）。
+type socks4Dialer struct {
+	addr    string
+	auth    *proxy.Auth
+	forward proxy.Dialer
+}
+
+func newSOCKS4Dialer(addr string, auth *proxy.Auth, forward proxy.Dialer) proxy.Dialer {
+	if forward == nil {
+		forward = proxy.Direct
+	}
+	return &socks4Dialer{addr: addr, auth: auth, forward: forward}
+}
+
+func (d *socks4Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+func (d *socks4Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := dialForward(ctx, d.forward, network, d.addr)
+	if err != nil {
+		return nil, fmt.Errorf("连接 SOCKS4 代理失败: %v", err)
+	}
+	doneDeadline := withHandshakeDeadline(ctx, conn)
+	defer doneDeadline()
+
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("解析目标地址失败: %v", err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("解析目标端口失败: %v", err)
+	}
+
+	ip := net.ParseIP(host)
+	userID := ""
+	if d.auth != nil {
+		userID = d.auth.User
+	}
+
+	req := make([]byte, 0, 32)
+	req = append(req, 0x04, 0x01) // VN=4, CD=1(CONNECT)
+	req = append(req, byte(port>>8), byte(port))
+
+	ipv4 := ip.To4()
+	if ipv4 == nil {
+		// SOCKS4A：IP 字段填充 0.0.0.1，并在 userID 之后附加目标域名
+		req = append(req, 0, 0, 0, 1)
+		req = append(req, []byte(userID)...)
+		req = append(req, 0)
+		req = append(req, []byte(host)...)
+		req = append(req, 0)
+	} else {
+		req = append(req, ipv4...)
+		req = append(req, []byte(userID)...)
+		req = append(req, 0)
+	}
+
+	if _, err := conn.Write(req); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("写入 SOCKS4 请求失败: %v", err)
+	}
+
+	resp := make([]byte, 8)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("读取 SOCKS4 响应失败: %v", err)
+	}
+	if resp[1] != 0x5a {
+		conn.Close()
+		return nil, fmt.Errorf("SOCKS4 代理拒绝连接，状态码: 0x%02x", resp[1])
+	}
+
+	return conn, nil
+}
+
+// --- HTTP/HTTPS CONNECT ---
+
+// httpConnectDialer 通过向上游代理发送 HTTP CONNECT 请求来建立隧道；
+// useTLS 为 true 时（代理类型为 https）先与代理完成 TLS 握手，再在加密通道上发送 CONNECT。
+type httpConnectDialer struct {
+	addr    string
+	auth    *proxy.Auth
+	forward proxy.Dialer
+	useTLS  bool
+}
+
+func newHTTPConnectDialer(addr string, auth *proxy.Auth, forward proxy.Dialer, useTLS bool) proxy.Dialer {
+	if forward == nil {
+		forward = proxy.Direct
+	}
+	return &httpConnectDialer{addr: addr, auth: auth, forward: forward, useTLS: useTLS}
+}
+
+func (d *httpConnectDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+func (d *httpConnectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := dialForward(ctx, d.forward, network, d.addr)
+	if err != nil {
+		return nil, fmt.Errorf("连接 HTTP 代理失败: %v", err)
+	}
+	doneDeadline := withHandshakeDeadline(ctx, conn)
+	defer doneDeadline()
+
+	if d.useTLS {
+		proxyHost, _, err := net.SplitHostPort(d.addr)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("解析代理地址失败: %v", err)
+		}
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyHost})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("与 HTTPS 代理握手失败: %v", err)
+		}
+		conn = tlsConn
+	}
+
+	connectLine := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", address, address)
+	if d.auth != nil {
+		connectLine += fmt.Sprintf("Proxy-Authorization: Basic %s\r\n", base64.StdEncoding.EncodeToString([]byte(d.auth.User+":"+d.auth.Password)))
+	}
+	connectLine += "\r\n"
+
+	if _, err := conn.Write([]byte(connectLine)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("写入 CONNECT 请求失败: %v", err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("读取 CONNECT 响应失败: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("HTTP 代理拒绝连接，状态码: %d", resp.StatusCode)
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenBucket 是一个按分钟限速的令牌桶，用于控制对单个目标（ip:port）的尝试频率。
+type tokenBucket struct {
+	mu         sync.Mutex
+	capacity   float64
+	tokens     float64
+	ratePerSec float64
+	last       time.Time
+}
+
+func newTokenBucket(perMinute int) *tokenBucket {
+	return &tokenBucket{
+		capacity:   float64(perMinute),
+		tokens:     float64(perMinute),
+		ratePerSec: float64(perMinute) / 60.0,
+		last:       time.Now(),
+	}
+}
+
+// Allow 尝试消费一个令牌，返回是否允许本次操作。
+func (b *tokenBucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.ratePerSec
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// hostRateLimiter 按 ip:port 维护独立的令牌桶。
+type hostRateLimiter struct {
+	mu        sync.Mutex
+	perMinute int
+	buckets   map[string]*tokenBucket
+}
+
+func newHostRateLimiter(perMinute int) *hostRateLimiter {
+	return &hostRateLimiter{
+		perMinute: perMinute,
+		buckets:   make(map[string]*tokenBucket),
+	}
+}
+
+func (l *hostRateLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = newTokenBucket(l.perMinute)
+		l.buckets[key] = bucket
+	}
+	l.mu.Unlock()
+
+	return bucket.Allow()
+}
@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"batch-connector/internal/crypto"
+	"batch-connector/internal/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+const maskedCredential = "****"
+
+// scanner 抽象了 *sql.Row 与 *sql.Rows 共有的 Scan 方法，
+// 使行级别的解析逻辑可以在单行查询与多行查询之间复用。
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// CredentialCipher 对 pass（以及可选的 user）字段做静态加密，cryptor 为 nil 时按明文直通。
+type CredentialCipher struct {
+	cryptor     *crypto.Cryptor
+	encryptUser bool
+}
+
+// NewCredentialCipher 创建一个凭据加解密器，cryptor 为 nil 表示不启用加密。
+func NewCredentialCipher(cryptor *crypto.Cryptor, encryptUser bool) *CredentialCipher {
+	return &CredentialCipher{cryptor: cryptor, encryptUser: encryptUser}
+}
+
+// encryptForStore 返回写入数据库时应使用的 pass/user 值。
+func (c *CredentialCipher) encryptForStore(conn *models.Connection) (pass, user string, err error) {
+	return c.encryptPassUser(conn.Pass, conn.User)
+}
+
+// encryptPassUser 按配置加密 pass（以及启用时的 user），未配置加密时原样返回。
+func (c *CredentialCipher) encryptPassUser(pass, user string) (string, string, error) {
+	if c == nil || c.cryptor == nil {
+		return pass, user, nil
+	}
+
+	encPass, err := c.cryptor.Encrypt(pass)
+	if err != nil {
+		return "", "", err
+	}
+
+	encUser := user
+	if c.encryptUser {
+		encUser, err = c.cryptor.Encrypt(user)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return encPass, encUser, nil
+}
+
+// decryptRow 原地解密 conn 中已从数据库读出的 pass/user 字段，
+// 返回该行是否为加密上线前遗留的明文（需要迁移重写）。
+// 对看起来像密文但解密失败的字段直接报错，而不是把密文原样当作明文返回——
+// 调用方据此拨测只会用一串乱码去登录，不如让错误暴露出来。
+func (c *CredentialCipher) decryptRow(conn *models.Connection) (migrated bool, err error) {
+	if c == nil || c.cryptor == nil {
+		return false, nil
+	}
+
+	if c.cryptor.LooksEncrypted(conn.Pass) {
+		plain, err := c.cryptor.Decrypt(conn.Pass)
+		if err != nil {
+			return false, fmt.Errorf("解密 pass 失败(id=%s): %v", conn.ID, err)
+		}
+		conn.Pass = plain
+	} else {
+		migrated = true
+	}
+
+	if c.encryptUser {
+		if c.cryptor.LooksEncrypted(conn.User) {
+			plain, err := c.cryptor.Decrypt(conn.User)
+			if err != nil {
+				return false, fmt.Errorf("解密 user 失败(id=%s): %v", conn.ID, err)
+			}
+			conn.User = plain
+		} else {
+			migrated = true
+		}
+	}
+
+	return migrated, nil
+}
+
+// mask 将 conn 中的敏感字段替换为掩码，用于非 reveal 模式下的列表展示。
+func (c *CredentialCipher) mask(conn *models.Connection) {
+	conn.Pass = maskedCredential
+	if c != nil && c.encryptUser {
+		conn.User = maskedCredential
+	}
+}
+
+// scanConnection 将一行查询结果解析为 Connection 对象。
+// reveal 为 false 时跳过解密、直接掩码，避免批量列表场景下意外解出明文；
+// 返回值 migrated 表示该行 pass/user 是加密上线前的遗留明文，调用方应重新加密写回。
+func scanConnection(s scanner, codec *CredentialCipher, reveal bool) (conn *models.Connection, migrated bool, err error) {
+	conn = &models.Connection{}
+	var logsJSON string
+	var createdAtStr string
+	// connected_at 在未连接时为 NULL（见 connectionToValues），需用 NullString 接收
+	var connectedAt sql.NullString
+
+	err = s.Scan(
+		&conn.ID,
+		&conn.Type,
+		&conn.IP,
+		&conn.Port,
+		&conn.User,
+		&conn.Pass,
+		&conn.Status,
+		&conn.Message,
+		&conn.Result,
+		&logsJSON,
+		&createdAtStr,
+		&connectedAt,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 解析日志 JSON
+	if logsJSON != "" {
+		if err := json.Unmarshal([]byte(logsJSON), &conn.Logs); err != nil {
+			conn.Logs = []string{}
+		}
+	} else {
+		conn.Logs = []string{}
+	}
+
+	// 解析时间，驱动返回的格式不尽相同，这里兼容 RFC3339 与驱动原生时间字符串
+	if createdAtStr != "" {
+		if t, err := parseTime(createdAtStr); err == nil {
+			conn.CreatedAt = t
+		}
+	}
+	if connectedAt.Valid && connectedAt.String != "" {
+		if t, err := parseTime(connectedAt.String); err == nil {
+			conn.ConnectedAt = t
+		}
+	}
+
+	if reveal {
+		migrated, err = codec.decryptRow(conn)
+		if err != nil {
+			log.Printf("凭据解密失败，跳过该行: %v", err)
+			return nil, false, err
+		}
+	} else {
+		codec.mask(conn)
+	}
+
+	return conn, migrated, nil
+}
+
+// parseTime 依次尝试常见的时间格式，兼容不同驱动对 DATETIME/TIMESTAMPTZ 的字符串表示。
+func parseTime(value string) (time.Time, error) {
+	layouts := []string{
+		time.RFC3339,
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05",
+	}
+	var lastErr error
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		} else {
+			lastErr = err
+		}
+	}
+	return time.Time{}, lastErr
+}
+
+// timeFormatter 按驱动的时间列类型格式化时间戳，由各驱动提供，与其 Update 方法保持一致
+// （sqlite/mysql 用字符串，postgres 用原生 time.Time）。
+type timeFormatter func(t time.Time) interface{}
+
+// connectionToValues 将 Connection 对象转换为按列顺序排列的数据库值，所有驱动共用同一字段顺序。
+// pass（以及按配置启用时的 user）在写入前经 codec 加密；ConnectedAt 为零值时写入 nil（NULL），
+// 而不是空字符串——MySQL/Postgres 的 DATETIME/TIMESTAMPTZ 列不接受 ""。
+func connectionToValues(conn *models.Connection, codec *CredentialCipher, formatTime timeFormatter) ([]interface{}, error) {
+	logsJSON := "[]"
+	if len(conn.Logs) > 0 {
+		jsonData, err := json.Marshal(conn.Logs)
+		if err != nil {
+			return nil, err
+		}
+		logsJSON = string(jsonData)
+	}
+
+	createdAt := formatTime(conn.CreatedAt)
+	var connectedAt interface{}
+	if !conn.ConnectedAt.IsZero() {
+		connectedAt = formatTime(conn.ConnectedAt)
+	}
+
+	pass, user, err := codec.encryptForStore(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return []interface{}{
+		conn.ID,
+		conn.Type,
+		conn.IP,
+		conn.Port,
+		user,
+		pass,
+		conn.Status,
+		conn.Message,
+		conn.Result,
+		logsJSON,
+		createdAt,
+		connectedAt,
+	}, nil
+}
+
+const selectColumns = "id, type, ip, port, user, pass, status, message, result, logs, created_at, connected_at"
+
+const insertColumns = "id, type, ip, port, user, pass, status, message, result, logs, created_at, connected_at"
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"batch-connector/internal/config"
+	"batch-connector/internal/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+const mysqlCreateTableSQL = `
+CREATE TABLE IF NOT EXISTS connections (
+	id VARCHAR(64) PRIMARY KEY,
+	type VARCHAR(32) NOT NULL,
+	ip VARCHAR(64) NOT NULL,
+	port VARCHAR(16) NOT NULL,
+	user TEXT,
+	pass TEXT,
+	status VARCHAR(32) NOT NULL DEFAULT 'pending',
+	message TEXT,
+	result TEXT,
+	logs LONGTEXT,
+	created_at DATETIME NOT NULL,
+	connected_at DATETIME NULL,
+	INDEX idx_type (type),
+	INDEX idx_status (status),
+	INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+
+// mysqlStorage 是 Storage 的 MySQL 实现。
+type mysqlStorage struct {
+	db    *sql.DB
+	codec *CredentialCipher
+}
+
+func newMySQLStorage(cfg *config.Database, codec *CredentialCipher) (Storage, error) {
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("mysql 驱动需要配置 database.dsn")
+	}
+
+	db, err := sql.Open("mysql", cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %v", err)
+	}
+
+	applyPoolSettings(db, cfg)
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("数据库连接测试失败: %v", err)
+	}
+
+	if _, err := db.Exec(mysqlCreateTableSQL); err != nil {
+		return nil, fmt.Errorf("创建表失败: %v", err)
+	}
+
+	log.Printf("数据库初始化成功(mysql): %s", config.MaskDSN(cfg.DSN))
+	return &mysqlStorage{db: db, codec: codec}, nil
+}
+
+// mysqlTimeFormat 格式化为 DATETIME 列接受的 "2006-01-02 15:04:05" 字符串。
+func mysqlTimeFormat(t time.Time) interface{} {
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func (s *mysqlStorage) Add(conn *models.Connection) error {
+	values, err := connectionToValues(conn, s.codec, mysqlTimeFormat)
+	if err != nil {
+		return fmt.Errorf("序列化连接数据失败: %v", err)
+	}
+
+	insertSQL := fmt.Sprintf(`INSERT INTO connections (%s) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, insertColumns)
+	if _, err := s.db.Exec(insertSQL, values...); err != nil {
+		return fmt.Errorf("插入连接失败: %v", err)
+	}
+	return nil
+}
+
+func (s *mysqlStorage) Get(id string, reveal bool) (*models.Connection, bool) {
+	querySQL := fmt.Sprintf(`SELECT %s FROM connections WHERE id = ?`, selectColumns)
+	row := s.db.QueryRow(querySQL, id)
+	conn, migrated, err := scanConnection(row, s.codec, reveal)
+	if err != nil {
+		return nil, false
+	}
+	if migrated {
+		s.reencryptCredentials(conn)
+	}
+	return conn, true
+}
+
+func (s *mysqlStorage) List(reveal bool) []*models.Connection {
+	querySQL := fmt.Sprintf(`SELECT %s FROM connections ORDER BY created_at DESC`, selectColumns)
+	rows, err := s.db.Query(querySQL)
+	if err != nil {
+		return []*models.Connection{}
+	}
+	defer rows.Close()
+	return s.collectRows(rows, reveal)
+}
+
+func (s *mysqlStorage) ListByType(connType string, reveal bool) []*models.Connection {
+	querySQL := fmt.Sprintf(`SELECT %s FROM connections WHERE type = ? ORDER BY created_at DESC`, selectColumns)
+	rows, err := s.db.Query(querySQL, connType)
+	if err != nil {
+		return []*models.Connection{}
+	}
+	defer rows.Close()
+	return s.collectRows(rows, reveal)
+}
+
+// collectRows 先读完整个结果集再迁移遗留明文凭据，避免在 *sql.Rows 仍在迭代时
+// 对同一张表发起 UPDATE——当 max_open 较小（甚至为 1）时，这会让 Exec 等待迭代中
+// 的 Rows 占用的唯一连接，造成死锁。
+func (s *mysqlStorage) collectRows(rows *sql.Rows, reveal bool) []*models.Connection {
+	connections := []*models.Connection{}
+	var toMigrate []*models.Connection
+	for rows.Next() {
+		conn, migrated, err := scanConnection(rows, s.codec, reveal)
+		if err != nil {
+			continue
+		}
+		if migrated {
+			toMigrate = append(toMigrate, conn)
+		}
+		connections = append(connections, conn)
+	}
+
+	for _, conn := range toMigrate {
+		s.reencryptCredentials(conn)
+	}
+	return connections
+}
+
+// reencryptCredentials 将加密上线前遗留的明文 pass/user 重新加密写回，conn 中已是解密后的明文。
+func (s *mysqlStorage) reencryptCredentials(conn *models.Connection) {
+	pass, user, err := s.codec.encryptForStore(conn)
+	if err != nil {
+		log.Printf("迁移加密凭据失败(id=%s): %v", conn.ID, err)
+		return
+	}
+	if _, err := s.db.Exec(`UPDATE connections SET pass = ?, user = ? WHERE id = ?`, pass, user, conn.ID); err != nil {
+		log.Printf("迁移加密凭据写回失败(id=%s): %v", conn.ID, err)
+	}
+}
+
+func (s *mysqlStorage) Update(conn *models.Connection) error {
+	logsJSON := "[]"
+	if len(conn.Logs) > 0 {
+		jsonData, err := json.Marshal(conn.Logs)
+		if err != nil {
+			return fmt.Errorf("序列化日志失败: %v", err)
+		}
+		logsJSON = string(jsonData)
+	}
+
+	var connectedAt interface{}
+	if !conn.ConnectedAt.IsZero() {
+		connectedAt = conn.ConnectedAt.Format("2006-01-02 15:04:05")
+	}
+
+	updateSQL := `UPDATE connections SET
+		status = ?, message = ?, result = ?, logs = ?, connected_at = ?
+		WHERE id = ?`
+
+	_, err := s.db.Exec(updateSQL, conn.Status, conn.Message, conn.Result, logsJSON, connectedAt, conn.ID)
+	if err != nil {
+		return fmt.Errorf("更新连接失败: %v", err)
+	}
+	return nil
+}
+
+func (s *mysqlStorage) UpdateInfo(id, connType, ip, port, user, pass string) error {
+	encPass, encUser, err := s.codec.encryptPassUser(pass, user)
+	if err != nil {
+		return fmt.Errorf("加密凭据失败: %v", err)
+	}
+
+	updateSQL := `UPDATE connections SET
+		type = ?, ip = ?, port = ?, user = ?, pass = ?
+		WHERE id = ?`
+
+	if _, err := s.db.Exec(updateSQL, connType, ip, port, encUser, encPass, id); err != nil {
+		return fmt.Errorf("更新连接信息失败: %v", err)
+	}
+	return nil
+}
+
+func (s *mysqlStorage) Delete(id string) bool {
+	result, err := s.db.Exec(`DELETE FROM connections WHERE id = ?`, id)
+	if err != nil {
+		return false
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false
+	}
+	return rowsAffected > 0
+}
+
+func (s *mysqlStorage) DeleteBatch(ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	placeholders := strings.Repeat("?,", len(ids))
+	placeholders = placeholders[:len(placeholders)-1]
+
+	deleteSQL := fmt.Sprintf("DELETE FROM connections WHERE id IN (%s)", placeholders)
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	result, err := s.db.Exec(deleteSQL, args...)
+	if err != nil {
+		return 0, fmt.Errorf("批量删除连接失败: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("获取删除行数失败: %v", err)
+	}
+	return int(rowsAffected), nil
+}
+
+func (s *mysqlStorage) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"batch-connector/internal/config"
+	"batch-connector/internal/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const postgresCreateTableSQL = `
+CREATE TABLE IF NOT EXISTS connections (
+	id TEXT PRIMARY KEY,
+	type TEXT NOT NULL,
+	ip TEXT NOT NULL,
+	port TEXT NOT NULL,
+	"user" TEXT,
+	pass TEXT,
+	status TEXT NOT NULL DEFAULT 'pending',
+	message TEXT,
+	result TEXT,
+	logs JSONB,
+	created_at TIMESTAMPTZ NOT NULL,
+	connected_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_type ON connections(type);
+CREATE INDEX IF NOT EXISTS idx_status ON connections(status);
+CREATE INDEX IF NOT EXISTS idx_created_at ON connections(created_at);
+`
+
+const postgresSelectColumns = `id, type, ip, port, "user", pass, status, message, result, logs, created_at, connected_at`
+
+// postgresStorage 是 Storage 的 PostgreSQL 实现。
+type postgresStorage struct {
+	db    *sql.DB
+	codec *CredentialCipher
+}
+
+func newPostgresStorage(cfg *config.Database, codec *CredentialCipher) (Storage, error) {
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("postgres 驱动需要配置 database.dsn")
+	}
+
+	db, err := sql.Open("postgres", cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %v", err)
+	}
+
+	applyPoolSettings(db, cfg)
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("数据库连接测试失败: %v", err)
+	}
+
+	if _, err := db.Exec(postgresCreateTableSQL); err != nil {
+		return nil, fmt.Errorf("创建表失败: %v", err)
+	}
+
+	log.Printf("数据库初始化成功(postgres): %s", config.MaskDSN(cfg.DSN))
+	return &postgresStorage{db: db, codec: codec}, nil
+}
+
+// postgresTimeFormat 原样返回 time.Time，交由 lib/pq 编码为 TIMESTAMPTZ。
+func postgresTimeFormat(t time.Time) interface{} {
+	return t
+}
+
+func (s *postgresStorage) Add(conn *models.Connection) error {
+	values, err := connectionToValues(conn, s.codec, postgresTimeFormat)
+	if err != nil {
+		return fmt.Errorf("序列化连接数据失败: %v", err)
+	}
+
+	insertSQL := fmt.Sprintf(`INSERT INTO connections (%s) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`, postgresSelectColumns)
+	if _, err := s.db.Exec(insertSQL, values...); err != nil {
+		return fmt.Errorf("插入连接失败: %v", err)
+	}
+	return nil
+}
+
+func (s *postgresStorage) Get(id string, reveal bool) (*models.Connection, bool) {
+	querySQL := fmt.Sprintf(`SELECT %s FROM connections WHERE id = $1`, postgresSelectColumns)
+	row := s.db.QueryRow(querySQL, id)
+	conn, migrated, err := scanConnection(row, s.codec, reveal)
+	if err != nil {
+		return nil, false
+	}
+	if migrated {
+		s.reencryptCredentials(conn)
+	}
+	return conn, true
+}
+
+func (s *postgresStorage) List(reveal bool) []*models.Connection {
+	querySQL := fmt.Sprintf(`SELECT %s FROM connections ORDER BY created_at DESC`, postgresSelectColumns)
+	rows, err := s.db.Query(querySQL)
+	if err != nil {
+		return []*models.Connection{}
+	}
+	defer rows.Close()
+	return s.collectRows(rows, reveal)
+}
+
+func (s *postgresStorage) ListByType(connType string, reveal bool) []*models.Connection {
+	querySQL := fmt.Sprintf(`SELECT %s FROM connections WHERE type = $1 ORDER BY created_at DESC`, postgresSelectColumns)
+	rows, err := s.db.Query(querySQL, connType)
+	if err != nil {
+		return []*models.Connection{}
+	}
+	defer rows.Close()
+	return s.collectRows(rows, reveal)
+}
+
+// collectRows 先读完整个结果集再迁移遗留明文凭据，避免在 *sql.Rows 仍在迭代时
+// 对同一张表发起 UPDATE——当 max_open 较小（甚至为 1）时，这会让 Exec 等待迭代中
+// 的 Rows 占用的唯一连接，造成死锁。
+func (s *postgresStorage) collectRows(rows *sql.Rows, reveal bool) []*models.Connection {
+	connections := []*models.Connection{}
+	var toMigrate []*models.Connection
+	for rows.Next() {
+		conn, migrated, err := scanConnection(rows, s.codec, reveal)
+		if err != nil {
+			continue
+		}
+		if migrated {
+			toMigrate = append(toMigrate, conn)
+		}
+		connections = append(connections, conn)
+	}
+
+	for _, conn := range toMigrate {
+		s.reencryptCredentials(conn)
+	}
+	return connections
+}
+
+// reencryptCredentials 将加密上线前遗留的明文 pass/user 重新加密写回，conn 中已是解密后的明文。
+func (s *postgresStorage) reencryptCredentials(conn *models.Connection) {
+	pass, user, err := s.codec.encryptForStore(conn)
+	if err != nil {
+		log.Printf("迁移加密凭据失败(id=%s): %v", conn.ID, err)
+		return
+	}
+	if _, err := s.db.Exec(`UPDATE connections SET pass = $1, "user" = $2 WHERE id = $3`, pass, user, conn.ID); err != nil {
+		log.Printf("迁移加密凭据写回失败(id=%s): %v", conn.ID, err)
+	}
+}
+
+func (s *postgresStorage) Update(conn *models.Connection) error {
+	logsJSON := "[]"
+	if len(conn.Logs) > 0 {
+		jsonData, err := json.Marshal(conn.Logs)
+		if err != nil {
+			return fmt.Errorf("序列化日志失败: %v", err)
+		}
+		logsJSON = string(jsonData)
+	}
+
+	var connectedAt interface{}
+	if !conn.ConnectedAt.IsZero() {
+		connectedAt = conn.ConnectedAt
+	}
+
+	updateSQL := `UPDATE connections SET
+		status = $1, message = $2, result = $3, logs = $4, connected_at = $5
+		WHERE id = $6`
+
+	_, err := s.db.Exec(updateSQL, conn.Status, conn.Message, conn.Result, logsJSON, connectedAt, conn.ID)
+	if err != nil {
+		return fmt.Errorf("更新连接失败: %v", err)
+	}
+	return nil
+}
+
+func (s *postgresStorage) UpdateInfo(id, connType, ip, port, user, pass string) error {
+	encPass, encUser, err := s.codec.encryptPassUser(pass, user)
+	if err != nil {
+		return fmt.Errorf("加密凭据失败: %v", err)
+	}
+
+	updateSQL := `UPDATE connections SET
+		type = $1, ip = $2, port = $3, "user" = $4, pass = $5
+		WHERE id = $6`
+
+	if _, err := s.db.Exec(updateSQL, connType, ip, port, encUser, encPass, id); err != nil {
+		return fmt.Errorf("更新连接信息失败: %v", err)
+	}
+	return nil
+}
+
+func (s *postgresStorage) Delete(id string) bool {
+	result, err := s.db.Exec(`DELETE FROM connections WHERE id = $1`, id)
+	if err != nil {
+		return false
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false
+	}
+	return rowsAffected > 0
+}
+
+func (s *postgresStorage) DeleteBatch(ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	deleteSQL := fmt.Sprintf("DELETE FROM connections WHERE id IN (%s)", strings.Join(placeholders, ","))
+
+	result, err := s.db.Exec(deleteSQL, args...)
+	if err != nil {
+		return 0, fmt.Errorf("批量删除连接失败: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("获取删除行数失败: %v", err)
+	}
+	return int(rowsAffected), nil
+}
+
+func (s *postgresStorage) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"batch-connector/internal/config"
+	"batch-connector/internal/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const dbFileName = "connections.db"
+
+const sqliteCreateTableSQL = `
+CREATE TABLE IF NOT EXISTS connections (
+	id TEXT PRIMARY KEY,
+	type TEXT NOT NULL,
+	ip TEXT NOT NULL,
+	port TEXT NOT NULL,
+	user TEXT,
+	pass TEXT,
+	status TEXT NOT NULL DEFAULT 'pending',
+	message TEXT,
+	result TEXT,
+	logs TEXT,
+	created_at TEXT NOT NULL,
+	connected_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_type ON connections(type);
+CREATE INDEX IF NOT EXISTS idx_status ON connections(status);
+CREATE INDEX IF NOT EXISTS idx_created_at ON connections(created_at);
+`
+
+// sqliteStorage 是 Storage 的 SQLite 实现，保持重构前的默认行为。
+type sqliteStorage struct {
+	db    *sql.DB
+	codec *CredentialCipher
+}
+
+func newSQLiteStorage(cfg *config.Database, codec *CredentialCipher) (Storage, error) {
+	dbPath := cfg.DSN
+	if dbPath == "" {
+		dbPath = getDBPath()
+	}
+
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		log.Printf("数据库文件不存在，将创建新数据库: %s", dbPath)
+		if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+			log.Printf("创建数据库目录失败: %v", err)
+		}
+	}
+
+	dsnPath := strings.ReplaceAll(dbPath, "\\", "/")
+	dsn := fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", dsnPath)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %v", err)
+	}
+
+	applyPoolSettings(db, cfg)
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("数据库连接测试失败: %v", err)
+	}
+
+	if _, err := db.Exec(sqliteCreateTableSQL); err != nil {
+		return nil, fmt.Errorf("创建表失败: %v", err)
+	}
+
+	log.Printf("数据库初始化成功(sqlite): %s", dbPath)
+	return &sqliteStorage{db: db, codec: codec}, nil
+}
+
+// getDBPath 获取数据库文件路径
+func getDBPath() string {
+	// 尝试获取可执行文件所在目录
+	exePath, err := os.Executable()
+	if err == nil {
+		exeDir := filepath.Dir(exePath)
+		return filepath.Join(exeDir, dbFileName)
+	}
+	// 如果获取失败，使用当前工作目录
+	return dbFileName
+}
+
+// sqliteTimeFormat 格式化为 connected_at/created_at TEXT 列使用的 RFC3339 字符串。
+func sqliteTimeFormat(t time.Time) interface{} {
+	return t.Format(time.RFC3339)
+}
+
+func (s *sqliteStorage) Add(conn *models.Connection) error {
+	values, err := connectionToValues(conn, s.codec, sqliteTimeFormat)
+	if err != nil {
+		return fmt.Errorf("序列化连接数据失败: %v", err)
+	}
+
+	insertSQL := fmt.Sprintf(`INSERT INTO connections (%s) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, insertColumns)
+	if _, err := s.db.Exec(insertSQL, values...); err != nil {
+		return fmt.Errorf("插入连接失败: %v", err)
+	}
+	return nil
+}
+
+func (s *sqliteStorage) Get(id string, reveal bool) (*models.Connection, bool) {
+	querySQL := fmt.Sprintf(`SELECT %s FROM connections WHERE id = ?`, selectColumns)
+	row := s.db.QueryRow(querySQL, id)
+	conn, migrated, err := scanConnection(row, s.codec, reveal)
+	if err != nil {
+		return nil, false
+	}
+	if migrated {
+		s.reencryptCredentials(conn)
+	}
+	return conn, true
+}
+
+func (s *sqliteStorage) List(reveal bool) []*models.Connection {
+	querySQL := fmt.Sprintf(`SELECT %s FROM connections ORDER BY created_at DESC`, selectColumns)
+	return s.queryAll(querySQL, reveal)
+}
+
+func (s *sqliteStorage) ListByType(connType string, reveal bool) []*models.Connection {
+	querySQL := fmt.Sprintf(`SELECT %s FROM connections WHERE type = ? ORDER BY created_at DESC`, selectColumns)
+	rows, err := s.db.Query(querySQL, connType)
+	if err != nil {
+		return []*models.Connection{}
+	}
+	defer rows.Close()
+	return s.collectRows(rows, reveal)
+}
+
+func (s *sqliteStorage) queryAll(querySQL string, reveal bool) []*models.Connection {
+	rows, err := s.db.Query(querySQL)
+	if err != nil {
+		return []*models.Connection{}
+	}
+	defer rows.Close()
+	return s.collectRows(rows, reveal)
+}
+
+// collectRows 先读完整个结果集再迁移遗留明文凭据，避免在 *sql.Rows 仍在迭代时
+// 对同一张表发起 UPDATE——SQLite 单写者模型下这会导致 "database is locked"。
+func (s *sqliteStorage) collectRows(rows *sql.Rows, reveal bool) []*models.Connection {
+	connections := []*models.Connection{}
+	var toMigrate []*models.Connection
+	for rows.Next() {
+		conn, migrated, err := scanConnection(rows, s.codec, reveal)
+		if err != nil {
+			continue
+		}
+		if migrated {
+			toMigrate = append(toMigrate, conn)
+		}
+		connections = append(connections, conn)
+	}
+
+	for _, conn := range toMigrate {
+		s.reencryptCredentials(conn)
+	}
+	return connections
+}
+
+// reencryptCredentials 将加密上线前遗留的明文 pass/user 重新加密写回，conn 中已是解密后的明文。
+func (s *sqliteStorage) reencryptCredentials(conn *models.Connection) {
+	pass, user, err := s.codec.encryptForStore(conn)
+	if err != nil {
+		log.Printf("迁移加密凭据失败(id=%s): %v", conn.ID, err)
+		return
+	}
+	if _, err := s.db.Exec(`UPDATE connections SET pass = ?, user = ? WHERE id = ?`, pass, user, conn.ID); err != nil {
+		log.Printf("迁移加密凭据写回失败(id=%s): %v", conn.ID, err)
+	}
+}
+
+func (s *sqliteStorage) Update(conn *models.Connection) error {
+	logsJSON := "[]"
+	if len(conn.Logs) > 0 {
+		jsonData, err := json.Marshal(conn.Logs)
+		if err != nil {
+			return fmt.Errorf("序列化日志失败: %v", err)
+		}
+		logsJSON = string(jsonData)
+	}
+
+	connectedAtStr := ""
+	if !conn.ConnectedAt.IsZero() {
+		connectedAtStr = conn.ConnectedAt.Format(time.RFC3339)
+	}
+
+	updateSQL := `UPDATE connections SET
+		status = ?, message = ?, result = ?, logs = ?, connected_at = ?
+		WHERE id = ?`
+
+	_, err := s.db.Exec(updateSQL, conn.Status, conn.Message, conn.Result, logsJSON, connectedAtStr, conn.ID)
+	if err != nil {
+		return fmt.Errorf("更新连接失败: %v", err)
+	}
+	return nil
+}
+
+func (s *sqliteStorage) UpdateInfo(id, connType, ip, port, user, pass string) error {
+	encPass, encUser, err := s.codec.encryptPassUser(pass, user)
+	if err != nil {
+		return fmt.Errorf("加密凭据失败: %v", err)
+	}
+
+	updateSQL := `UPDATE connections SET
+		type = ?, ip = ?, port = ?, user = ?, pass = ?
+		WHERE id = ?`
+
+	if _, err := s.db.Exec(updateSQL, connType, ip, port, encUser, encPass, id); err != nil {
+		return fmt.Errorf("更新连接信息失败: %v", err)
+	}
+	return nil
+}
+
+func (s *sqliteStorage) Delete(id string) bool {
+	result, err := s.db.Exec(`DELETE FROM connections WHERE id = ?`, id)
+	if err != nil {
+		return false
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false
+	}
+	return rowsAffected > 0
+}
+
+func (s *sqliteStorage) DeleteBatch(ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	placeholders := strings.Repeat("?,", len(ids))
+	placeholders = placeholders[:len(placeholders)-1]
+
+	deleteSQL := fmt.Sprintf("DELETE FROM connections WHERE id IN (%s)", placeholders)
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	result, err := s.db.Exec(deleteSQL, args...)
+	if err != nil {
+		return 0, fmt.Errorf("批量删除连接失败: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("获取删除行数失败: %v", err)
+	}
+	return int(rowsAffected), nil
+}
+
+func (s *sqliteStorage) Close() error {
+	return s.db.Close()
+}
+
+// applyPoolSettings 应用连接池配置，0 表示沿用驱动默认值。
+func applyPoolSettings(db *sql.DB, cfg *config.Database) {
+	if cfg.MaxOpen > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpen)
+	}
+	if cfg.MaxIdle > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdle)
+	}
+}
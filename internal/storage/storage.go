@@ -0,0 +1,37 @@
+// Package storage 提供连接信息的持久化抽象，支持 SQLite/MySQL/PostgreSQL 多种后端。
+package storage
+
+import (
+	"batch-connector/internal/config"
+	"batch-connector/internal/models"
+	"fmt"
+)
+
+// Storage 定义连接记录的持久化接口，具体实现由各数据库驱动提供。
+// Get/List/ListByType 的 reveal 参数为 false 时返回掩码后的凭据，
+// 只有显式传入 true 才会触发解密，避免批量列表场景意外泄露明文。
+type Storage interface {
+	Add(conn *models.Connection) error
+	Get(id string, reveal bool) (*models.Connection, bool)
+	List(reveal bool) []*models.Connection
+	ListByType(connType string, reveal bool) []*models.Connection
+	Update(conn *models.Connection) error
+	UpdateInfo(id, connType, ip, port, user, pass string) error
+	Delete(id string) bool
+	DeleteBatch(ids []string) (int, error)
+	Close() error
+}
+
+// New 根据配置创建对应驱动的 Storage 实例，codec 为 nil 时不启用凭据加密。
+func New(cfg *config.Database, codec *CredentialCipher) (Storage, error) {
+	switch cfg.Driver {
+	case "", "sqlite":
+		return newSQLiteStorage(cfg, codec)
+	case "mysql":
+		return newMySQLStorage(cfg, codec)
+	case "postgres", "postgresql":
+		return newPostgresStorage(cfg, codec)
+	default:
+		return nil, fmt.Errorf("不支持的数据库驱动: %s", cfg.Driver)
+	}
+}